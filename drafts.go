@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// moreMarker, left in a post's markdown, cuts the feed excerpt at that
+// point instead of falling back to the first paragraph.
+const moreMarker = "<!--more-->"
+
+// publishedPosts drops drafts and posts dated in the future, unless
+// includeDrafts is set (the -drafts flag).
+func publishedPosts(posts Posts, includeDrafts bool) Posts {
+	if includeDrafts {
+		return posts
+	}
+
+	now := time.Now()
+	var out Posts
+	for _, p := range posts {
+		if p.Draft || p.Date.After(now) {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// excerptFor computes a short plain-text excerpt from a post's fully
+// rendered HTML body, for use as a feed Item's Description: everything
+// before an explicit <!--more--> cut, or the first paragraph otherwise,
+// truncated to maxRunes on a word boundary.
+func excerptFor(html []byte, maxRunes int) (string, error) {
+	src := string(html)
+	idx := strings.Index(src, moreMarker)
+	if idx != -1 {
+		src = src[:idx]
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(src))
+	if err != nil {
+		return "", err
+	}
+
+	var text string
+	if idx == -1 {
+		text = strings.TrimSpace(doc.Find("p").First().Text())
+	}
+	if text == "" {
+		text = strings.TrimSpace(doc.Text())
+	}
+
+	return truncateWords(text, maxRunes), nil
+}
+
+// truncateWords shortens s to at most maxRunes runes, backing up to the
+// previous word boundary rather than splitting mid-word, and appends "…"
+// when it cuts anything.
+func truncateWords(s string, maxRunes int) string {
+	r := []rune(s)
+	if len(r) <= maxRunes {
+		return s
+	}
+
+	cut := maxRunes
+	for cut > 0 && r[cut] != ' ' {
+		cut--
+	}
+	if cut == 0 {
+		cut = maxRunes
+	}
+
+	return strings.TrimSpace(string(r[:cut])) + "…"
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/adrg/frontmatter"
+)
+
+// AuthorRE is a regex to grab our Authors
+var AuthorRE = regexp.MustCompile(`^author:\s(.*)$`)
+
+// TitleRE matches our article title
+var TitleRE = regexp.MustCompile(`^title:\s(.*)$`)
+
+// DateRE matches our article date
+var DateRE = regexp.MustCompile(`^date:\s(.*)$`)
+
+// TagRE matches the tags for a given post
+var TagRE = regexp.MustCompile(`^tags:\s(.*)$`)
+
+// DescRE matches the descriptoin for a given post
+var DescRE = regexp.MustCompile(`^description:\s(.*)$`)
+
+// dateLayouts are tried in order when parsing the `date` frontmatter field,
+// since YAML/TOML authors tend to write plain dates rather than RFC1123.
+var dateLayouts = []string{
+	time.RFC1123,
+	time.RFC3339,
+	"2006-01-02",
+}
+
+// frontMatter is the shape of the `---`/`+++` block at the top of a post,
+// decoded directly onto by github.com/adrg/frontmatter before falling back
+// to the legacy per-line regex parser.
+type frontMatter struct {
+	Title       string      `yaml:"title" toml:"title"`
+	Description string      `yaml:"description" toml:"description"`
+	Date        interface{} `yaml:"date" toml:"date"`
+	Author      string      `yaml:"author" toml:"author"`
+	Tags        []string    `yaml:"tags" toml:"tags"`
+	Draft       bool        `yaml:"draft" toml:"draft"`
+	Slug        string      `yaml:"slug" toml:"slug"`
+}
+
+// hasFrontMatter reports whether data opens with a YAML (`---`) or TOML
+// (`+++`) frontmatter delimiter.
+func hasFrontMatter(data []byte) bool {
+	trimmed := bytes.TrimLeft(data, "\r\n\t ")
+	return bytes.HasPrefix(trimmed, []byte("---")) || bytes.HasPrefix(trimmed, []byte("+++"))
+}
+
+// parseDate tries each of dateLayouts in turn, returning the first match.
+func parseDate(s string) (time.Time, error) {
+	var err error
+	for _, layout := range dateLayouts {
+		var t time.Time
+		t, err = time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("could not parse date %q: %v", s, err)
+}
+
+// frontMatterDate normalizes a decoded `date` field to a time.Time. TOML's
+// bare date/datetime literals (e.g. `date = 2024-01-15`, with no quotes)
+// and YAML timestamps decode straight to time.Time; quoted strings in
+// either format fall back to parseDate.
+func frontMatterDate(v interface{}) (time.Time, error) {
+	switch d := v.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return d, nil
+	case string:
+		if d == "" {
+			return time.Time{}, nil
+		}
+		return parseDate(d)
+	default:
+		return time.Time{}, fmt.Errorf("unsupported date value %v (%T)", d, d)
+	}
+}
+
+// loadFrontMatter decodes a `---`/`+++` delimited header into fm and copies
+// it onto p, leaving the remainder of the file as p.Body.
+func (p *Post) loadFrontMatter(data []byte) error {
+	var fm frontMatter
+
+	rest, err := frontmatter.Parse(bytes.NewReader(data), &fm)
+	if err != nil {
+		return fmt.Errorf("decoding frontmatter: %v", err)
+	}
+
+	p.Title = fm.Title
+	p.Description = fm.Description
+	p.Slug = fm.Slug
+	p.Draft = fm.Draft
+	p.Author.Parse(fm.Author)
+
+	p.Date, err = frontMatterDate(fm.Date)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range fm.Tags {
+		p.Tags = append(p.Tags, &Tag{Name: strings.TrimSpace(tag)})
+	}
+
+	p.Body = rest
+	return nil
+}
+
+// loadLegacy is the original per-line regex parser, kept as a compatibility
+// fallback for posts that predate frontmatter delimiters.
+func (p *Post) loadLegacy(data []byte) error {
+	var err error
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		var line = scanner.Bytes()
+		useLine := true
+		if AuthorRE.Match(line) {
+			aline := AuthorRE.ReplaceAllString(string(line), "$1")
+			p.Author.Parse(aline)
+			fmt.Printf("Author: %s %s (%s)\n", p.Author.FName, p.Author.LName, p.Author.Email)
+			useLine = false
+		}
+		if TitleRE.Match(line) {
+			p.Title = TitleRE.ReplaceAllString(string(line), "$1")
+			fmt.Printf("Title: %s\n", p.Title)
+			useLine = false
+		}
+		if DateRE.Match(line) {
+			d := DateRE.ReplaceAllString(string(line), "$1")
+			p.Date, err = parseDate(d)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Date: %s\n", p.Date)
+			useLine = false
+		}
+
+		if TagRE.Match(line) {
+			ts := TagRE.ReplaceAllString(string(line), "$1")
+			for _, tag := range strings.Split(ts, ",") {
+				var t Tag
+				t.Name = strings.TrimSpace(tag)
+				p.Tags = append(p.Tags, &t)
+			}
+			fmt.Printf("Tags: %s\n", p.Tags.Join())
+			useLine = false
+		}
+
+		if DescRE.Match(line) {
+			p.Description = DescRE.ReplaceAllString(string(line), "$1")
+			fmt.Printf("Description: %s\n", p.Description)
+			useLine = false
+		}
+
+		if useLine {
+			p.Body = append(p.Body, line...)
+			p.Body = append(p.Body, 10)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// LoadFromFile takes the File of a given page and loads the markdown for
+// rendering. Posts opening with a `---`/`+++` frontmatter block are decoded
+// directly onto Post; older posts using the bespoke `key: value` header
+// lines fall back to the legacy regex parser.
+func (p *Post) LoadFromFile(f string) error {
+	data, err := os.ReadFile(f)
+	if err != nil {
+		return err
+	}
+
+	if hasFrontMatter(data) {
+		return p.loadFrontMatter(data)
+	}
+	return p.loadLegacy(data)
+}
@@ -0,0 +1,99 @@
+// Package config loads the TOML site configuration that drives a boring
+// build: the site's title and URL, author details, where to read posts and
+// templates from and where to write the generated site, and the feed and
+// markdown options that used to be hardcoded in main.go.
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// MarkdownConfig toggles the goldmark extensions used to render posts.
+type MarkdownConfig struct {
+	GFM            bool   `toml:"gfm"`
+	Footnote       bool   `toml:"footnote"`
+	Typographer    bool   `toml:"typographer"`
+	HighlightTheme string `toml:"highlight_theme"`
+}
+
+// Config is the full set of knobs for a boring site build.
+type Config struct {
+	Title       string `toml:"title"`
+	BaseURL     string `toml:"base_url"`
+	AuthorName  string `toml:"author_name"`
+	AuthorEmail string `toml:"author_email"`
+	Copyright   string `toml:"copyright"`
+
+	SourceDir   string `toml:"source_dir"`
+	TemplateDir string `toml:"template_dir"`
+	OutputDir   string `toml:"output_dir"`
+
+	// OriginalDomain and DomainStartDate feed the RFC 4151 tag: URIs used
+	// as stable Atom entry IDs. DomainStartDate is the date (YYYY-MM-DD)
+	// OriginalDomain came under the author's control.
+	OriginalDomain  string `toml:"original_domain"`
+	DomainStartDate string `toml:"domain_start_date"`
+	PerTagFeeds     bool   `toml:"per_tag_feeds"`
+
+	Markdown MarkdownConfig `toml:"markdown"`
+
+	// ExcerptRunes bounds the feed excerpt, whether it is taken from an
+	// explicit <!--more--> cut or from the first paragraph.
+	ExcerptRunes int `toml:"excerpt_runes"`
+
+	// CSP holds Content-Security-Policy directives (e.g. "default-src" ->
+	// "'self'") sent as headers by the dev server.
+	CSP map[string]string `toml:"csp"`
+}
+
+// Default returns the configuration boring runs with when no -config file
+// is given, matching the site it originally shipped with.
+func Default() *Config {
+	return &Config{
+		Title:       "deftly.net",
+		BaseURL:     "https://deftly.net",
+		AuthorName:  "Aaron Bieber",
+		AuthorEmail: "aaron@bolddaemon.com",
+		Copyright:   "This work is copyright © Aaron Bieber",
+
+		SourceDir:   "src",
+		TemplateDir: "tmpl",
+		OutputDir:   "dst",
+
+		OriginalDomain:  "deftly.net",
+		DomainStartDate: "2009-01-01",
+		PerTagFeeds:     true,
+
+		Markdown: MarkdownConfig{
+			GFM:            true,
+			Footnote:       true,
+			Typographer:    true,
+			HighlightTheme: "github",
+		},
+
+		ExcerptRunes: 280,
+	}
+}
+
+// Load reads a TOML config file at path and overlays it onto Default. An
+// empty path returns Default unchanged.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path == "" {
+		return cfg, nil
+	}
+
+	if _, err := toml.DecodeFile(path, cfg); err != nil {
+		return nil, fmt.Errorf("loading config %s: %v", path, err)
+	}
+
+	return cfg, nil
+}
+
+// DomainStart parses DomainStartDate for use in RFC 4151 tag: URIs.
+func (c *Config) DomainStart() (time.Time, error) {
+	return time.Parse("2006-01-02", c.DomainStartDate)
+}
@@ -1,24 +1,20 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
 	"html/template"
 	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
-	"os/exec"
 	"path"
 	"regexp"
 	"sort"
 	"strings"
 	"time"
 
-	"github.com/fsnotify/fsnotify"
 	. "github.com/gorilla/feeds"
-	"github.com/russross/blackfriday"
+	"github.com/qbit/boring/internal/config"
 )
 
 var templ *template.Template
@@ -64,21 +60,6 @@ type content struct {
 	Author User
 }
 
-// AuthorRE is a regex to grab our Authors
-var AuthorRE = regexp.MustCompile(`^author:\s(.*)$`)
-
-// TitleRE matches our article title
-var TitleRE = regexp.MustCompile(`^title:\s(.*)$`)
-
-// DateRE matches our article date
-var DateRE = regexp.MustCompile(`^date:\s(.*)$`)
-
-// TagRE matches the tags for a given post
-var TagRE = regexp.MustCompile(`^tags:\s(.*)$`)
-
-// DescRE matches the descriptoin for a given post
-var DescRE = regexp.MustCompile(`^description:\s(.*)$`)
-
 // Tag represents a specific tag for an article
 type Tag struct {
 	ID      int
@@ -136,75 +117,18 @@ type Post struct {
 	Signature   []byte
 	Tags        Tags
 	URL         string
+	Draft       bool
+	Slug        string
 }
 
-// HTML returns converted MD to HTML
-func (p *Post) HTML() {
-	p.Body = blackfriday.MarkdownCommon(p.Body)
-}
-
-// LoadFromFile takes the File of a given page and loads the markdown for rendering
-func (p *Post) LoadFromFile(f string) error {
-	file, err := os.Open(f)
-	if err != nil {
-		return err
-	}
-	scanner := bufio.NewScanner(file)
-	if err != nil {
-		return err
-	}
-
-	for scanner.Scan() {
-		var line = scanner.Bytes()
-		useLine := true
-		if AuthorRE.Match(line) {
-			aline := AuthorRE.ReplaceAllString(string(line), "$1")
-			p.Author.Parse(aline)
-			fmt.Printf("Author: %s %s (%s)\n", p.Author.FName, p.Author.LName, p.Author.Email)
-			useLine = false
-		}
-		if TitleRE.Match(line) {
-			p.Title = TitleRE.ReplaceAllString(string(line), "$1")
-			fmt.Printf("Title: %s\n", p.Title)
-			useLine = false
-		}
-		if DateRE.Match(line) {
-			d := DateRE.ReplaceAllString(string(line), "$1")
-			p.Date, err = time.Parse(time.RFC1123, d)
-			if err != nil {
-				log.Printf("error in '%s'\n", f)
-				log.Fatal(err)
-			}
-			fmt.Printf("Date: %s\n", p.Date)
-			useLine = false
-		}
-
-		if TagRE.Match(line) {
-			ts := TagRE.ReplaceAllString(string(line), "$1")
-			for _, tag := range strings.Split(ts, ",") {
-				var t Tag
-				t.Name = strings.TrimSpace(tag)
-				p.Tags = append(p.Tags, &t)
-			}
-			fmt.Printf("Tags: %s\n", p.Tags.Join())
-			useLine = false
-		}
-
-		if DescRE.Match(line) {
-			p.Description = DescRE.ReplaceAllString(string(line), "$1")
-			fmt.Printf("Description: %s\n", p.Description)
-			useLine = false
-		}
-
-		if useLine {
-			p.Body = append(p.Body, line...)
-			p.Body = append(p.Body, 10)
-		}
-	}
-
+// HTML converts p.Body from markdown to HTML in place, using the default
+// Renderer.
+func (p *Post) HTML() error {
+	out, err := defaultRenderer.Render(p.Body)
 	if err != nil {
 		return err
 	}
+	p.Body = out
 	return nil
 }
 
@@ -223,19 +147,23 @@ func (p Posts) Swap(i, j int) {
 	p[i], p[j] = p[j], p[i]
 }
 
-func renderPost(f string, path string) (Post, error) {
-	var err error
+func renderPost(cfg *config.Config, f string, path string) (Post, error) {
 	p := Post{}
 
-	p.LoadFromFile(f)
-
-	if err != nil {
-		log.Fatal(err)
+	if err := p.LoadFromFile(f); err != nil {
+		return p, fmt.Errorf("loading %s: %v", f, err)
 	}
 
-	p.HTML()
+	if err := p.HTML(); err != nil {
+		return p, fmt.Errorf("rendering %s: %v", f, err)
+	}
 	p.URL = "/" + md2html(path)
 
+	if p.Author.FName == "" && p.Author.LName == "" && p.Author.Email == "" {
+		p.Author.FName = cfg.AuthorName
+		p.Author.Email = cfg.AuthorEmail
+	}
+
 	return p, nil
 }
 
@@ -258,164 +186,201 @@ func md2html(f string) string {
 	return strings.Replace(f, ".md", ".html", -1)
 }
 
+// configPath scans args for -config/--config before the rest of the flags
+// are registered, since their defaults are sourced from the loaded Config.
+func configPath(args []string) string {
+	for i, a := range args {
+		switch {
+		case a == "-config" || a == "--config":
+			if i+1 < len(args) {
+				return args[i+1]
+			}
+		case strings.HasPrefix(a, "-config="):
+			return strings.TrimPrefix(a, "-config=")
+		case strings.HasPrefix(a, "--config="):
+			return strings.TrimPrefix(a, "--config=")
+		}
+	}
+	return ""
+}
+
 func main() {
 	var err error
 	// extrasys.Pledge("stdio wpath rpath cpath", nil)
 
-	var watch = flag.Bool("w", false, "Enable 'watch' mode. Requires 'wdir' and 'wcmd'.")
-	var watchDir = flag.String("wdir", "", "watch a directory for changes, run command when change happens.")
-	var watchCmd = flag.String("wcmd", "", "command to run when changes are detected in 'wdir'.")
-	var srvPort = flag.String("port", ":8080", "Port to serve the static files on.")
+	cfg, err := config.Load(configPath(os.Args[1:]))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	flag.String("config", "", "path to a boring.toml site config")
+	var watch = flag.Bool("w", false, "Enable 'watch' mode: rebuild in-process and live-reload the browser on changes under 'wdir'.")
+	var watchDir = flag.String("wdir", "", "directory (and subdirectories) to watch for changes; defaults to 'src'.")
+	var srvPort = flag.String("port", ":8080", "Port to serve the built site on.")
+	var srcFlag = flag.String("src", cfg.SourceDir, "directory containing markdown source posts")
+	var tmplFlag = flag.String("tmpl", cfg.TemplateDir, "directory containing html templates")
+	var dstFlag = flag.String("dst", cfg.OutputDir, "directory to write the generated site to")
+	var domainFlag = flag.String("domain", cfg.OriginalDomain, "original domain used for Atom tag: URIs")
+	var domainStartFlag = flag.String("domain-start", cfg.DomainStartDate, "date (YYYY-MM-DD) the -domain name came under the author's control, used for RFC4151 tag URIs")
+	var drafts = flag.Bool("drafts", false, "include draft and future-dated posts in the index, archive and feeds")
 
 	flag.Parse()
 
-	if !*watch {
-		if len(os.Args) < 2 {
-			fmt.Println("Wrong number of arguments")
-			os.Exit(1)
-		}
+	// Positional args remain supported as an override, for back-compat
+	// with `boring src tmpl dst`.
+	src, tmpl, dst := *srcFlag, *tmplFlag, *dstFlag
+	if flag.NArg() >= 3 {
+		src, tmpl, dst = flag.Arg(0), flag.Arg(1), flag.Arg(2)
+	}
+	domain := *domainFlag
+	domainStart, err := time.Parse("2006-01-02", *domainStartFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-		src := os.Args[1]
-		tmpl := os.Args[2]
-		dst := os.Args[3]
+	defaultRenderer = NewGoldmarkRenderer(RendererOptions{
+		GFM:            cfg.Markdown.GFM,
+		Footnote:       cfg.Markdown.Footnote,
+		Typographer:    cfg.Markdown.Typographer,
+		HighlightTheme: cfg.Markdown.HighlightTheme,
+	})
 
-		templ, err = template.New("boring").Funcs(funcMap).ParseGlob(tmpl + "/*.html")
-		if err != nil {
+	if !*watch {
+		if err := buildSite(cfg, src, tmpl, dst, domain, domainStart, *drafts); err != nil {
 			log.Fatal(err)
 		}
+		return
+	}
 
-		log.Printf("Generating static html from %s to %s\n", src, dst)
+	if *watchDir == "" {
+		*watchDir = src
+	}
+	if err := runWatch(cfg, *watchDir, tmpl, dst, domain, domainStart, *srvPort, *drafts); err != nil {
+		log.Fatal(err)
+	}
+}
 
-		files, err := ioutil.ReadDir(src)
-		if err != nil {
-			log.Fatal(err)
-		}
+// buildSite runs the full static-site build: render every post in src,
+// write index/about/contact/archive, the per-tag pages and feeds, and the
+// site-wide Atom/RSS feeds, using tmpl for templates and writing into dst.
+// Drafts and future-dated posts are excluded from everything but their own
+// post page unless includeDrafts is set.
+func buildSite(cfg *config.Config, src, tmpl, dst, domain string, domainStart time.Time, includeDrafts bool) error {
+	var err error
 
-		posts := Posts{}
-		for _, file := range files {
-			fn := file.Name()
-			srcFile := path.Join(src, fn)
-			dstFile := path.Join(dst, "/posts/", md2html(fn))
-			post, err := renderPost(srcFile, path.Join("posts/", fn))
-			fmt.Println("-----")
-			if err != nil {
-				log.Fatal(err)
-			}
+	if err := os.MkdirAll(path.Join(dst, "posts"), 0755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Join(dst, "tags"), 0755); err != nil {
+		return err
+	}
 
-			renderTemplate(dstFile, "default.html", struct {
-				Content Post
-			}{
-				post,
-			})
+	templ, err = template.New("boring").Funcs(funcMap).ParseGlob(tmpl + "/*.html")
+	if err != nil {
+		return err
+	}
 
-			posts = append(posts, &post)
-		}
+	log.Printf("Generating static html from %s to %s\n", src, dst)
 
-		sort.Sort(posts)
+	files, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
 
-		renderTemplate(path.Join(dst, "/index.html"), "index.html", content{
-			Title: "",
-			Posts: posts,
-		})
-		renderTemplate(path.Join(dst, "/about.html"), "about.html", content{
-			Title:  "About",
-			Author: posts[0].Author,
-		})
-		renderTemplate(path.Join(dst, "/contact.html"), "contact.html", content{
-			Title:  "Contact",
-			Author: posts[0].Author,
-		})
-		if len(posts) < 5 {
-			renderTemplate(path.Join(dst, "/archive.html"), "archive.html", content{
-				Title: "Archive",
-				Posts: posts,
-			})
-		} else {
-			renderTemplate(path.Join(dst, "/archive.html"), "archive.html", content{
-				Title: "Archive",
-				Posts: posts[5:],
-			})
+	posts := Posts{}
+	for _, file := range files {
+		fn := file.Name()
+		srcFile := path.Join(src, fn)
+		dstFile := path.Join(dst, "/posts/", md2html(fn))
+		post, err := renderPost(cfg, srcFile, path.Join("posts/", fn))
+		if err != nil {
+			return err
 		}
 
-		// TODO variablize all of this and shove it in some kind of config
+		renderTemplate(dstFile, "default.html", struct {
+			Content Post
+		}{
+			post,
+		})
 
-		latestDate := posts[0].Date
+		posts = append(posts, &post)
+	}
 
-		feed := &Feed{
-			Title:       "deftly.net - All posts",
-			Link:        &Link{Href: "https://deftly.net/"},
-			Description: "Personal blog of Aaron Bieber",
-			Author:      &Author{Name: "Aaron Bieber", Email: "aaron@bolddaemon.com"},
-			Created:     latestDate,
-			Copyright:   "This work is copyright © Aaron Bieber",
-		}
+	sort.Sort(posts)
+	visible := publishedPosts(posts, includeDrafts)
+
+	renderTemplate(path.Join(dst, "/index.html"), "index.html", content{
+		Title: "",
+		Posts: visible,
+	})
+	renderTemplate(path.Join(dst, "/about.html"), "about.html", content{
+		Title:  "About",
+		Author: posts[0].Author,
+	})
+	renderTemplate(path.Join(dst, "/contact.html"), "contact.html", content{
+		Title:  "Contact",
+		Author: posts[0].Author,
+	})
+	if len(visible) < 5 {
+		renderTemplate(path.Join(dst, "/archive.html"), "archive.html", content{
+			Title: "Archive",
+			Posts: visible,
+		})
+	} else {
+		renderTemplate(path.Join(dst, "/archive.html"), "archive.html", content{
+			Title: "Archive",
+			Posts: visible[5:],
+		})
+	}
 
-		for _, post := range posts {
-			var i = &Item{}
-			i.Title = post.Title
-			i.Description = string(post.Body)
-			i.Link = &Link{Href: "https://deftly.net" + post.URL}
-			i.Author = &Author{Name: post.Author.Combine(), Email: "aaron@bolddaemon.com"}
-			i.Created = post.Date
+	if err := renderTagPages(cfg, dst, visible, domain, domainStart, cfg.PerTagFeeds); err != nil {
+		return err
+	}
 
-			feed.Items = append(feed.Items, i)
-		}
+	var latestDate time.Time
+	if len(visible) > 0 {
+		latestDate = visible[0].Date
+	}
 
-		atomFile, err := os.Create(path.Join(dst, "atom.xml"))
-		if err != nil {
-			log.Fatal(err)
-		}
+	feed := &Feed{
+		Title:       cfg.Title + " - All posts",
+		Link:        &Link{Href: cfg.BaseURL + "/"},
+		Description: "Personal blog of " + cfg.AuthorName,
+		Author:      &Author{Name: cfg.AuthorName, Email: cfg.AuthorEmail},
+		Created:     latestDate,
+		Copyright:   cfg.Copyright,
+	}
 
-		rssFile, err := os.Create(path.Join(dst, "rss.xml"))
+	for _, post := range visible {
+		excerpt, err := excerptFor(post.Body, cfg.ExcerptRunes)
 		if err != nil {
-			log.Fatal(err)
+			return fmt.Errorf("excerpting %s: %v", post.URL, err)
 		}
 
-		feed.WriteAtom(atomFile)
-		feed.WriteRss(rssFile)
-	} else {
-		// Watch mode
-
-		go func() {
-			// Start a http server and serve the static dir
-			log.Printf("listening on https://localhost%s", *srvPort)
-			log.Fatal(
-				http.ListenAndServe(
-					*srvPort,
-					http.FileServer(http.Dir("static/")),
-				),
-			)
-		}()
-
-		watcher, err := fsnotify.NewWatcher()
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer watcher.Close()
-
-		done := make(chan bool)
-		go func() {
-			for {
-				select {
-				case event := <-watcher.Events:
-					if event.Op&fsnotify.Write == fsnotify.Write {
-						log.Println("modified file:", event.Name)
-						c := exec.Command(*watchCmd)
-
-						if err := c.Run(); err != nil {
-							fmt.Println("Error: ", err)
-						}
-					}
-				case err := <-watcher.Errors:
-					log.Fatal(err)
-				}
-			}
-		}()
+		var i = &Item{}
+		i.Id = tagURI(domain, domainStart, "posts/"+postSlug(post))
+		i.Title = post.Title
+		i.Description = excerpt
+		i.Content = string(post.Body)
+		i.Link = &Link{Href: cfg.BaseURL + post.URL}
+		i.Author = &Author{Name: post.Author.Combine(), Email: post.Author.Email}
+		i.Created = post.Date
 
-		err = watcher.Add(*watchDir)
-		if err != nil {
-			log.Fatal(err)
-		}
-		<-done
+		feed.Items = append(feed.Items, i)
+	}
+
+	atomFile, err := os.Create(path.Join(dst, "atom.xml"))
+	if err != nil {
+		return err
+	}
+
+	rssFile, err := os.Create(path.Join(dst, "rss.xml"))
+	if err != nil {
+		return err
+	}
+
+	if err := feed.WriteAtom(atomFile); err != nil {
+		return err
 	}
+	return feed.WriteRss(rssFile)
 }
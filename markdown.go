@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+
+	chromahtml "github.com/alecthomas/chroma/formatters/html"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting"
+	"github.com/yuin/goldmark/extension"
+)
+
+// RendererOptions controls which markdown extensions are enabled and how
+// fenced code blocks are highlighted. The zero value enables no
+// extension; an empty HighlightTheme falls back to "github".
+type RendererOptions struct {
+	GFM            bool
+	Footnote       bool
+	Typographer    bool
+	HighlightTheme string
+}
+
+// DefaultRendererOptions returns the options boring renders with when no
+// config overrides them.
+func DefaultRendererOptions() RendererOptions {
+	return RendererOptions{
+		GFM:            true,
+		Footnote:       true,
+		Typographer:    true,
+		HighlightTheme: "github",
+	}
+}
+
+// Renderer turns markdown source into HTML. It exists so the markdown
+// engine can be swapped without touching Post.
+type Renderer interface {
+	Render(src []byte) ([]byte, error)
+}
+
+// goldmarkRenderer is the default Renderer, built on goldmark with GFM,
+// footnotes, typographer and chroma syntax highlighting.
+type goldmarkRenderer struct {
+	md goldmark.Markdown
+}
+
+// NewGoldmarkRenderer builds a Renderer from opts.
+func NewGoldmarkRenderer(opts RendererOptions) Renderer {
+	var extensions []goldmark.Extender
+
+	if opts.GFM {
+		extensions = append(extensions, extension.GFM)
+	}
+	if opts.Footnote {
+		extensions = append(extensions, extension.Footnote)
+	}
+	if opts.Typographer {
+		extensions = append(extensions, extension.Typographer)
+	}
+
+	theme := opts.HighlightTheme
+	if theme == "" {
+		theme = "github"
+	}
+	extensions = append(extensions, highlighting.NewHighlighting(
+		highlighting.WithStyle(theme),
+		highlighting.WithFormatOptions(
+			chromahtml.WithLineNumbers(false),
+		),
+	))
+
+	md := goldmark.New(goldmark.WithExtensions(extensions...))
+
+	return &goldmarkRenderer{md: md}
+}
+
+// Render converts src from markdown to HTML.
+func (g *goldmarkRenderer) Render(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := g.md.Convert(src, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// defaultRenderer is used by Post.HTML. main() assigns it from the loaded
+// Config before any posts are rendered.
+var defaultRenderer Renderer = NewGoldmarkRenderer(DefaultRendererOptions())
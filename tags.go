@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	. "github.com/gorilla/feeds"
+	"github.com/qbit/boring/internal/config"
+)
+
+// tagSlug normalizes a tag name into the filesystem/URL-safe form used for
+// /tags/<slug>.html and /tags/<slug>.atom.
+func tagSlug(name string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), " ", "-"))
+}
+
+// groupByTag buckets posts by each of their tags, keyed by the tag's
+// canonical (un-slugified) name, each bucket sorted newest-first.
+func groupByTag(posts Posts) map[string]Posts {
+	byTag := map[string]Posts{}
+	for _, p := range posts {
+		for _, t := range p.Tags {
+			byTag[t.Name] = append(byTag[t.Name], p)
+		}
+	}
+	for _, tagged := range byTag {
+		sort.Sort(tagged)
+	}
+	return byTag
+}
+
+// tagSummary is the per-tag row shown on /tags/index.html.
+type tagSummary struct {
+	Name  string
+	Slug  string
+	Count int
+}
+
+// postSlug returns the identifier used in RFC 4151 tag URIs for p,
+// preferring the frontmatter Slug and falling back to the rendered URL's
+// base name.
+func postSlug(p *Post) string {
+	if p.Slug != "" {
+		return p.Slug
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(path.Base(p.URL), "/"), ".html")
+}
+
+// tagURI builds an RFC 4151 tag: URI of the form tag:<domain>,<date>:<resource>.
+func tagURI(domain string, domainStart time.Time, resource string) string {
+	return fmt.Sprintf("tag:%s,%s:%s", domain, domainStart.Format("2006-01-02"), resource)
+}
+
+// renderTagPages writes a page per tag under dst/tags, plus a
+// /tags/index.html summary of every tag and its post count. A per-tag Atom
+// feed is also written unless perTagFeeds is false.
+func renderTagPages(cfg *config.Config, dst string, posts Posts, domain string, domainStart time.Time, perTagFeeds bool) error {
+	byTag := groupByTag(posts)
+
+	var tags []tagSummary
+	for name, tagged := range byTag {
+		slug := tagSlug(name)
+		tags = append(tags, tagSummary{Name: name, Slug: slug, Count: len(tagged)})
+
+		renderTemplate(path.Join(dst, "tags", slug+".html"), "tag.html", content{
+			Title: name,
+			Posts: tagged,
+		})
+
+		if !perTagFeeds {
+			continue
+		}
+		if err := renderTagFeed(cfg, dst, name, slug, tagged, domain, domainStart); err != nil {
+			return err
+		}
+	}
+
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+
+	renderTemplate(path.Join(dst, "tags", "index.html"), "tags.html", struct {
+		Title string
+		Tags  []tagSummary
+	}{
+		Title: "Tags",
+		Tags:  tags,
+	})
+
+	return nil
+}
+
+// renderTagFeed writes dst/tags/<slug>.atom for a single tag.
+func renderTagFeed(cfg *config.Config, dst, name, slug string, posts Posts, domain string, domainStart time.Time) error {
+	feed := &Feed{
+		Title:       fmt.Sprintf("%s - posts tagged %q", domain, name),
+		Link:        &Link{Href: cfg.BaseURL + "/tags/" + slug},
+		Description: fmt.Sprintf("Posts tagged %q", name),
+		Id:          tagURI(domain, domainStart, "tags/"+slug),
+	}
+
+	if len(posts) > 0 {
+		feed.Created = posts[0].Date
+	}
+
+	for _, p := range posts {
+		excerpt, err := excerptFor(p.Body, cfg.ExcerptRunes)
+		if err != nil {
+			return fmt.Errorf("excerpting %s: %v", p.URL, err)
+		}
+
+		feed.Items = append(feed.Items, &Item{
+			Id:          tagURI(domain, domainStart, "posts/"+postSlug(p)),
+			Title:       p.Title,
+			Link:        &Link{Href: cfg.BaseURL + p.URL},
+			Author:      &Author{Name: p.Author.Combine(), Email: p.Author.Email},
+			Created:     p.Date,
+			Description: excerpt,
+			Content:     string(p.Body),
+		})
+	}
+
+	f, err := os.Create(path.Join(dst, "tags", slug+".atom"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return feed.WriteAtom(f)
+}
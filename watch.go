@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gorilla/websocket"
+	"github.com/qbit/boring/internal/config"
+)
+
+// rebuildDebounce coalesces a burst of fsnotify writes (e.g. an editor's
+// save-then-rename) into a single rebuild.
+const rebuildDebounce = 100 * time.Millisecond
+
+// liveReloadScript is injected into every served HTML page. It opens a
+// WebSocket to /_livereload and reloads the page on a "reload" message,
+// reconnecting if the dev server restarts.
+const liveReloadScript = `<script>(function(){
+  function connect() {
+    var proto = location.protocol === "https:" ? "wss://" : "ws://";
+    var ws = new WebSocket(proto + location.host + "/_livereload");
+    ws.onmessage = function(e) { if (e.data === "reload") { location.reload(); } };
+    ws.onclose = function() { setTimeout(connect, 1000); };
+  }
+  connect();
+})();</script>`
+
+// liveReloadHub tracks connected browsers and tells them to reload.
+type liveReloadHub struct {
+	upgrader websocket.Upgrader
+	mu       sync.Mutex
+	clients  map[*websocket.Conn]bool
+}
+
+func newLiveReloadHub() *liveReloadHub {
+	return &liveReloadHub{
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		clients: map[*websocket.Conn]bool{},
+	}
+}
+
+func (h *liveReloadHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("livereload upgrade:", err)
+		return
+	}
+
+	h.mu.Lock()
+	h.clients[conn] = true
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.clients, conn)
+		h.mu.Unlock()
+		conn.Close()
+	}()
+
+	// Drain the connection so we notice when the browser goes away; boring
+	// never expects messages from the client.
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func (h *liveReloadHub) reload() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.clients {
+		if err := c.WriteMessage(websocket.TextMessage, []byte("reload")); err != nil {
+			c.Close()
+			delete(h.clients, c)
+		}
+	}
+}
+
+// servingDir is swapped atomically after each successful rebuild so
+// in-flight requests always see a complete build, never a half-written one.
+type servingDir struct {
+	mu  sync.RWMutex
+	dir string
+}
+
+func (s *servingDir) set(dir string) {
+	s.mu.Lock()
+	s.dir = dir
+	s.mu.Unlock()
+}
+
+func (s *servingDir) get() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.dir
+}
+
+// liveReloadFileServer serves files out of dir.get(), injecting
+// liveReloadScript before </body> on HTML responses and, if csp is
+// non-empty, a Content-Security-Policy header built from it.
+func liveReloadFileServer(dir *servingDir, csp map[string]string) http.Handler {
+	cspHeader := buildCSPHeader(csp)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		http.FileServer(http.Dir(dir.get())).ServeHTTP(rec, r)
+
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		if cspHeader != "" {
+			w.Header().Set("Content-Security-Policy", cspHeader)
+		}
+
+		body := rec.Body.Bytes()
+		if strings.Contains(rec.Header().Get("Content-Type"), "text/html") {
+			body = injectBeforeBodyClose(body, []byte(liveReloadScript))
+			w.Header().Del("Content-Length")
+		}
+
+		w.WriteHeader(rec.Code)
+		w.Write(body)
+	})
+}
+
+// buildCSPHeader renders csp's directives into a Content-Security-Policy
+// header value, e.g. {"default-src": "'self'"} -> "default-src 'self'".
+// Directives are sorted for a stable header across rebuilds.
+func buildCSPHeader(csp map[string]string) string {
+	if len(csp) == 0 {
+		return ""
+	}
+
+	directives := make([]string, 0, len(csp))
+	for k := range csp {
+		directives = append(directives, k)
+	}
+	sort.Strings(directives)
+
+	parts := make([]string, 0, len(directives))
+	for _, k := range directives {
+		parts = append(parts, k+" "+csp[k])
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// injectBeforeBodyClose inserts script just before the last </body> in body,
+// or appends it if no closing body tag is found.
+func injectBeforeBodyClose(body, script []byte) []byte {
+	idx := bytes.LastIndex(body, []byte("</body>"))
+	if idx == -1 {
+		return append(body, script...)
+	}
+	out := make([]byte, 0, len(body)+len(script))
+	out = append(out, body[:idx]...)
+	out = append(out, script...)
+	out = append(out, body[idx:]...)
+	return out
+}
+
+// watchRecursive adds root and every subdirectory under it to watcher.
+func watchRecursive(watcher *fsnotify.Watcher, root string) error {
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return watcher.Add(p)
+		}
+		return nil
+	})
+}
+
+// runWatch builds the site in-process, serves it with live-reload injection
+// on srvPort, and rebuilds whenever a file under watchDir changes. It blocks
+// until SIGINT/SIGTERM, then shuts the HTTP server down gracefully.
+func runWatch(cfg *config.Config, watchDir, tmpl, dst, domain string, domainStart time.Time, srvPort string, includeDrafts bool) error {
+	buildRoot, err := ioutil.TempDir("", "boring-watch")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(buildRoot)
+
+	dir := &servingDir{}
+	hub := newLiveReloadHub()
+
+	rebuild := func() {
+		out := filepath.Join(buildRoot, time.Now().Format("20060102150405.000000000"))
+
+		if err := buildSite(cfg, watchDir, tmpl, out, domain, domainStart, includeDrafts); err != nil {
+			log.Println("rebuild failed:", err)
+			return
+		}
+
+		previous := dir.get()
+		dir.set(out)
+		if previous != "" {
+			os.RemoveAll(previous)
+		}
+
+		log.Println("rebuilt", out)
+		hub.reload()
+	}
+
+	rebuild()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := watchRecursive(watcher, watchDir); err != nil {
+		return err
+	}
+
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+					continue
+				}
+				log.Println("changed:", event.Name)
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(rebuildDebounce, rebuild)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Println("watcher error:", err)
+			}
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle("/_livereload", hub)
+	mux.Handle("/", liveReloadFileServer(dir, cfg.CSP))
+
+	srv := &http.Server{Addr: srvPort, Handler: mux}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		log.Println("shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Println("shutdown:", err)
+		}
+	}()
+
+	log.Printf("listening on http://localhost%s", srvPort)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}